@@ -0,0 +1,210 @@
+package controller
+
+import (
+	"sort"
+	"strings"
+
+	extv1beta1 "k8s.io/api/extensions/v1beta1"
+	networkingv1 "k8s.io/api/networking/v1"
+	networkingv1beta1 "k8s.io/api/networking/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+
+	gatewayapi "sigs.k8s.io/gateway-api/apis/v1beta1"
+)
+
+// sourceGVRs are the GroupVersionResources ingress-shim knows how to adapt
+// to ingressLike, in preference order: informers should be started for
+// whichever of these the cluster actually serves, per AvailableSources.
+var sourceGVRs = []schema.GroupVersionResource{
+	networkingv1.SchemeGroupVersion.WithResource("ingresses"),
+	networkingv1beta1.SchemeGroupVersion.WithResource("ingresses"),
+	extv1beta1.SchemeGroupVersion.WithResource("ingresses"),
+	gatewayapi.SchemeGroupVersion.WithResource("gateways"),
+	gatewayapi.SchemeGroupVersion.WithResource("httproutes"),
+}
+
+// AvailableSources queries the API server's discovery endpoint and returns
+// the subset of sourceGVRs that it actually serves, so the controller can
+// start an informer per available API instead of assuming every cluster has
+// networking.k8s.io/v1 or the Gateway API CRDs installed. The caller is
+// expected to start one informer per returned GVR against its own shared
+// informer factory and feed the resulting objects into Sync via the
+// appropriate ingressLike adapter (or, for httproutes, into the HTTPRoute
+// index consulted by gatewayIngress.TLSEntries).
+func AvailableSources(disco discovery.DiscoveryInterface) ([]schema.GroupVersionResource, error) {
+	var available []schema.GroupVersionResource
+	for _, gvr := range sourceGVRs {
+		resources, err := disco.ServerResourcesForGroupVersion(gvr.GroupVersion().String())
+		if err != nil {
+			// the group/version itself isn't served at all (e.g. Gateway API
+			// CRDs aren't installed) -- treat as "not available", not fatal.
+			continue
+		}
+		for _, r := range resources.APIResources {
+			if r.Name == gvr.Resource {
+				available = append(available, gvr)
+				break
+			}
+		}
+	}
+	return available, nil
+}
+
+// ingressLike is a source-agnostic view over the objects ingress-shim can
+// build Certificates from. It is implemented by extensions/v1beta1 and
+// networking.k8s.io Ingresses today, and by Gateway API Gateways, so that
+// Sync and the annotation-driven Certificate building logic below don't need
+// to care which API surfaced the request.
+type ingressLike interface {
+	metav1.Object
+	// runtime.Object is required so the object can be passed directly to
+	// the event recorder and owner-reference helpers.
+	runtime.Object
+	// GroupVersionKind identifies the concrete type, for use as the owner
+	// reference set on Certificates created for it.
+	GroupVersionKind() schema.GroupVersionKind
+	// TLSEntries returns one ingressLikeTLS per TLS block (or, for a Gateway,
+	// per listener certificateRef) that should have a Certificate created for
+	// it.
+	TLSEntries() []ingressLikeTLS
+}
+
+// ingressLikeTLS is the common shape of a single TLS block across all
+// supported ingressLike sources: a set of hosts and the Secret they should
+// be stored in.
+type ingressLikeTLS struct {
+	Hosts      []string
+	SecretName string
+}
+
+// extensionsIngress adapts an extensions/v1beta1 Ingress to ingressLike.
+type extensionsIngress struct {
+	*extv1beta1.Ingress
+}
+
+func (i extensionsIngress) GroupVersionKind() schema.GroupVersionKind {
+	return extv1beta1.SchemeGroupVersion.WithKind("Ingress")
+}
+
+func (i extensionsIngress) TLSEntries() []ingressLikeTLS {
+	entries := make([]ingressLikeTLS, len(i.Spec.TLS))
+	for idx, tls := range i.Spec.TLS {
+		entries[idx] = ingressLikeTLS{Hosts: tls.Hosts, SecretName: tls.SecretName}
+	}
+	return entries
+}
+
+// networkingV1Ingress adapts a networking.k8s.io/v1 Ingress to ingressLike.
+type networkingV1Ingress struct {
+	*networkingv1.Ingress
+}
+
+func (i networkingV1Ingress) GroupVersionKind() schema.GroupVersionKind {
+	return networkingv1.SchemeGroupVersion.WithKind("Ingress")
+}
+
+func (i networkingV1Ingress) TLSEntries() []ingressLikeTLS {
+	entries := make([]ingressLikeTLS, len(i.Spec.TLS))
+	for idx, tls := range i.Spec.TLS {
+		entries[idx] = ingressLikeTLS{Hosts: tls.Hosts, SecretName: tls.SecretName}
+	}
+	return entries
+}
+
+// networkingV1beta1Ingress adapts a networking.k8s.io/v1beta1 Ingress to
+// ingressLike.
+type networkingV1beta1Ingress struct {
+	*networkingv1beta1.Ingress
+}
+
+func (i networkingV1beta1Ingress) GroupVersionKind() schema.GroupVersionKind {
+	return networkingv1beta1.SchemeGroupVersion.WithKind("Ingress")
+}
+
+func (i networkingV1beta1Ingress) TLSEntries() []ingressLikeTLS {
+	entries := make([]ingressLikeTLS, len(i.Spec.TLS))
+	for idx, tls := range i.Spec.TLS {
+		entries[idx] = ingressLikeTLS{Hosts: tls.Hosts, SecretName: tls.SecretName}
+	}
+	return entries
+}
+
+// gatewayIngress adapts a Gateway API Gateway to ingressLike. Each
+// certificateRef of a listener with a TLS block becomes one TLS entry.
+// A listener's own Hostname is used when it's set to a concrete (non-empty,
+// non-wildcard) value; otherwise the hosts actually served are only known
+// once HTTPRoutes attach to the listener, so Routes is consulted instead.
+type gatewayIngress struct {
+	*gatewayapi.Gateway
+	// Routes are the HTTPRoutes observed to reference this Gateway via
+	// spec.parentRefs, as fed in by the HTTPRoute informer started by
+	// AvailableSources' caller.
+	Routes []*gatewayapi.HTTPRoute
+}
+
+func (g gatewayIngress) GroupVersionKind() schema.GroupVersionKind {
+	return gatewayapi.SchemeGroupVersion.WithKind("Gateway")
+}
+
+func (g gatewayIngress) TLSEntries() []ingressLikeTLS {
+	var entries []ingressLikeTLS
+	for _, l := range g.Spec.Listeners {
+		if l.TLS == nil {
+			continue
+		}
+		hosts := g.listenerHosts(l)
+		for _, ref := range l.TLS.CertificateRefs {
+			entries = append(entries, ingressLikeTLS{Hosts: hosts, SecretName: string(ref.Name)})
+		}
+	}
+	return entries
+}
+
+// listenerHosts resolves the hostnames served by listener l: its own
+// Hostname if concrete, otherwise the hostnames of any HTTPRoute attached to
+// it via spec.parentRefs. The result is sorted so that the host order built
+// into the resulting Certificate spec is stable across syncs regardless of
+// the order routes are returned by the lister/informer, matching the
+// ownership-aware comparison certificateNeedsUpdate relies on to avoid
+// resyncing the same Certificate forever.
+func (g gatewayIngress) listenerHosts(l gatewayapi.Listener) []string {
+	if l.Hostname != nil && *l.Hostname != "" && !strings.HasPrefix(string(*l.Hostname), "*") {
+		return []string{string(*l.Hostname)}
+	}
+
+	var hosts []string
+	seen := map[string]bool{}
+	for _, route := range g.Routes {
+		if !routeAttachedToGateway(route, g.Gateway) {
+			continue
+		}
+		for _, h := range route.Spec.Hostnames {
+			if seen[string(h)] {
+				continue
+			}
+			seen[string(h)] = true
+			hosts = append(hosts, string(h))
+		}
+	}
+	sort.Strings(hosts)
+	return hosts
+}
+
+// routeAttachedToGateway reports whether route names gw in one of its
+// spec.parentRefs. Per the Gateway API, a parentRef with no namespace refers
+// to a Gateway in the route's own namespace, not the Gateway's.
+func routeAttachedToGateway(route *gatewayapi.HTTPRoute, gw *gatewayapi.Gateway) bool {
+	for _, ref := range route.Spec.ParentRefs {
+		namespace := route.Namespace
+		if ref.Namespace != nil {
+			namespace = string(*ref.Namespace)
+		}
+		if string(ref.Name) == gw.Name && namespace == gw.Namespace {
+			return true
+		}
+	}
+	return false
+}
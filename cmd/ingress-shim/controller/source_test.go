@@ -0,0 +1,128 @@
+package controller
+
+import (
+	"reflect"
+	"testing"
+
+	extv1beta1 "k8s.io/api/extensions/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	gatewayapi "sigs.k8s.io/gateway-api/apis/v1beta1"
+)
+
+func TestExtensionsIngressTLSEntries(t *testing.T) {
+	ing := extensionsIngress{&extv1beta1.Ingress{
+		Spec: extv1beta1.IngressSpec{
+			TLS: []extv1beta1.IngressTLS{
+				{Hosts: []string{"example.com"}, SecretName: "example-tls"},
+			},
+		},
+	}}
+
+	want := []ingressLikeTLS{{Hosts: []string{"example.com"}, SecretName: "example-tls"}}
+	if got := ing.TLSEntries(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %#v, want %#v", got, want)
+	}
+}
+
+func hostnamePtr(h gatewayapi.Hostname) *gatewayapi.Hostname { return &h }
+func namespacePtr(n gatewayapi.Namespace) *gatewayapi.Namespace { return &n }
+
+func TestGatewayIngressListenerHosts(t *testing.T) {
+	gw := &gatewayapi.Gateway{
+		ObjectMeta: metav1.ObjectMeta{Name: "my-gw", Namespace: "ns-a"},
+	}
+
+	t.Run("concrete listener hostname wins without consulting routes", func(t *testing.T) {
+		g := gatewayIngress{Gateway: gw}
+		l := gatewayapi.Listener{Hostname: hostnamePtr("example.com")}
+		want := []string{"example.com"}
+		if got := g.listenerHosts(l); !reflect.DeepEqual(got, want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	})
+
+	t.Run("wildcard listener hostname falls back to attached routes", func(t *testing.T) {
+		route := &gatewayapi.HTTPRoute{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns-a"},
+			Spec: gatewayapi.HTTPRouteSpec{
+				CommonRouteSpec: gatewayapi.CommonRouteSpec{
+					ParentRefs: []gatewayapi.ParentReference{{Name: "my-gw"}},
+				},
+				Hostnames: []gatewayapi.Hostname{"b.example.com", "a.example.com"},
+			},
+		}
+		g := gatewayIngress{Gateway: gw, Routes: []*gatewayapi.HTTPRoute{route}}
+		l := gatewayapi.Listener{Hostname: hostnamePtr("*.example.com")}
+
+		// hosts must come back sorted: the attach order of g.Routes (informer
+		// list order) is not guaranteed stable across syncs, and an unsorted
+		// DNSNames slice would make certificateNeedsUpdate's DeepEqual flip
+		// between syncs for the same logical set of hosts.
+		want := []string{"a.example.com", "b.example.com"}
+		if got := g.listenerHosts(l); !reflect.DeepEqual(got, want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	})
+
+	t.Run("a route in another namespace with an unqualified parentRef does not attach", func(t *testing.T) {
+		route := &gatewayapi.HTTPRoute{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns-b"},
+			Spec: gatewayapi.HTTPRouteSpec{
+				CommonRouteSpec: gatewayapi.CommonRouteSpec{
+					// unqualified parentRef: per the Gateway API this means a
+					// Gateway named my-gw in the route's own namespace
+					// (ns-b), not in the Gateway's namespace (ns-a).
+					ParentRefs: []gatewayapi.ParentReference{{Name: "my-gw"}},
+				},
+				Hostnames: []gatewayapi.Hostname{"sneaky.example.com"},
+			},
+		}
+		g := gatewayIngress{Gateway: gw, Routes: []*gatewayapi.HTTPRoute{route}}
+		l := gatewayapi.Listener{Hostname: hostnamePtr("*.example.com")}
+
+		if got := g.listenerHosts(l); len(got) != 0 {
+			t.Errorf("got %v, want no hosts: an unqualified parentRef must not cross namespaces", got)
+		}
+	})
+
+	t.Run("a route with an explicit cross-namespace parentRef does attach", func(t *testing.T) {
+		route := &gatewayapi.HTTPRoute{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns-b"},
+			Spec: gatewayapi.HTTPRouteSpec{
+				CommonRouteSpec: gatewayapi.CommonRouteSpec{
+					ParentRefs: []gatewayapi.ParentReference{{Name: "my-gw", Namespace: namespacePtr("ns-a")}},
+				},
+				Hostnames: []gatewayapi.Hostname{"cross-ns.example.com"},
+			},
+		}
+		g := gatewayIngress{Gateway: gw, Routes: []*gatewayapi.HTTPRoute{route}}
+		l := gatewayapi.Listener{Hostname: hostnamePtr("*.example.com")}
+
+		want := []string{"cross-ns.example.com"}
+		if got := g.listenerHosts(l); !reflect.DeepEqual(got, want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	})
+}
+
+func TestGatewayIngressTLSEntriesSkipsListenersWithoutTLS(t *testing.T) {
+	g := gatewayIngress{Gateway: &gatewayapi.Gateway{
+		Spec: gatewayapi.GatewaySpec{
+			Listeners: []gatewayapi.Listener{
+				{Hostname: hostnamePtr("plain.example.com")},
+				{
+					Hostname: hostnamePtr("secure.example.com"),
+					TLS: &gatewayapi.GatewayTLSConfig{
+						CertificateRefs: []*gatewayapi.SecretObjectReference{{Name: "secure-tls"}},
+					},
+				},
+			},
+		},
+	}}
+
+	want := []ingressLikeTLS{{Hosts: []string{"secure.example.com"}, SecretName: "secure-tls"}}
+	if got := g.TLSEntries(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %#v, want %#v", got, want)
+	}
+}
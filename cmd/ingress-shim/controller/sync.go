@@ -2,14 +2,19 @@ package controller
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"reflect"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/golang/glog"
 	corev1 "k8s.io/api/core/v1"
-	extv1beta1 "k8s.io/api/extensions/v1beta1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 
 	"github.com/jetstack/cert-manager/pkg/apis/certmanager/v1alpha1"
 )
@@ -33,65 +38,254 @@ const (
 	// acmeIssuerDNS01ProviderNameAnnotation can be used to override the default dns01 provider
 	// configured on the issuer if the challenge type is set to dns01
 	acmeIssuerDNS01ProviderNameAnnotation = "certmanager.k8s.io/acme-dns01-provider"
+	// acmeIssuerChallengeSolversAnnotation allows a single Ingress to use
+	// different ACME challenge solvers for different TLS hosts, e.g. dns01
+	// for wildcard hosts and http01 for the apex host. The value is a
+	// JSON-encoded list of solverHostRule, and every host of every TLS
+	// entry on the Ingress must be matched by exactly one rule.
+	acmeIssuerChallengeSolversAnnotation = "certmanager.k8s.io/acme-challenge-solvers"
+	// commonNameAnnotation can be used to set the CommonName on a Certificate
+	// generated for an Ingress. Applies to all issuer kinds.
+	commonNameAnnotation = "certmanager.k8s.io/common-name"
+	// organizationAnnotation can be used to set the Organization on a
+	// Certificate generated for an Ingress, as a comma-separated list.
+	// Applies to all issuer kinds.
+	organizationAnnotation = "certmanager.k8s.io/organization"
+	// durationAnnotation can be used to set the Duration on a Certificate
+	// generated for an Ingress. Applies to all issuer kinds.
+	durationAnnotation = "certmanager.k8s.io/duration"
+	// keySizeAnnotation can be used to set the KeySize on a Certificate
+	// generated for an Ingress. Applies to all issuer kinds.
+	keySizeAnnotation = "certmanager.k8s.io/key-size"
+	// keyAlgorithmAnnotation can be used to set the KeyAlgorithm on a
+	// Certificate generated for an Ingress. Applies to all issuer kinds.
+	keyAlgorithmAnnotation = "certmanager.k8s.io/key-algorithm"
+	// ipSANsAnnotation can be used to set the IPAddresses on a Certificate
+	// generated for an Ingress, as a comma-separated list. Applies to all
+	// issuer kinds.
+	ipSANsAnnotation = "certmanager.k8s.io/ip-sans"
+	// ingressClassAnnotation is the standard annotation used to select which
+	// ingress controller (and, here, which ingress-shim deployment) should
+	// act on an Ingress resource. It is compared against the controller's
+	// configured --ingress-class option.
+	ingressClassAnnotation = "kubernetes.io/ingress.class"
 )
 
-var ingressGVK = extv1beta1.SchemeGroupVersion.WithKind("Ingress")
+// solverHostRule describes the challenge solver to use for a set of hosts,
+// as specified via the acmeIssuerChallengeSolversAnnotation annotation.
+type solverHostRule struct {
+	Hosts  []string `json:"hosts"`
+	HTTP01 bool     `json:"http01,omitempty"`
+	DNS01  string   `json:"dns01,omitempty"`
+}
 
-func (c *Controller) Sync(ctx context.Context, ing *extv1beta1.Ingress) error {
-	if !shouldSync(ing) {
-		glog.Infof("Not syncing ingress %s/%s as it does not contain necessary annotations", ing.Namespace, ing.Name)
+func (c *Controller) Sync(ctx context.Context, ing ingressLike) error {
+	if !c.ingressClassMatches(ing) {
+		// this Ingress belongs to a different --ingress-class deployment of
+		// ingress-shim. Certificates owned by this Ingress are keyed on its
+		// UID, not on which deployment's class matched, so a non-matching
+		// deployment must not garbage collect them out from under the
+		// deployment that does match.
+		glog.Infof("Not syncing ingress %s/%s as it does not match the configured ingress class", ing.GetNamespace(), ing.GetName())
 		return nil
 	}
 
+	if !c.shouldSync(ing) {
+		glog.Infof("Not syncing ingress %s/%s as it does not contain necessary annotations", ing.GetNamespace(), ing.GetName())
+		// the Ingress may have previously had cert-manager annotations that
+		// have since been removed, in which case we should clean up any
+		// Certificates we created for it.
+		return c.garbageCollectCertificates(ing, nil)
+	}
+
 	crts, err := c.buildCertificates(ing)
 	if err != nil {
 		return err
 	}
 
+	var errs []error
 	for _, crt := range crts {
-		_, err := c.CMClient.CertmanagerV1alpha1().Certificates(crt.Namespace).Create(crt)
+		if err := c.reconcileCertificate(ing, crt); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if err := c.garbageCollectCertificates(ing, crts); err != nil {
+		errs = append(errs, err)
+	}
+
+	return utilerrors.NewAggregate(errs)
+}
+
+// reconcileCertificate creates the Certificate described by crt if it does
+// not yet exist, or updates it in place if its spec has drifted from the
+// desired state built from ing. It refuses to mutate a Certificate that
+// exists but is not owned by ing.
+func (c *Controller) reconcileCertificate(ing ingressLike, crt *v1alpha1.Certificate) error {
+	existingCrt, err := c.certificateLister.Certificates(crt.Namespace).Get(crt.Name)
+	if apierrors.IsNotFound(err) {
+		_, err = c.CMClient.CertmanagerV1alpha1().Certificates(crt.Namespace).Create(crt)
 		if err != nil {
 			return err
 		}
 		c.Recorder.Eventf(ing, corev1.EventTypeNormal, "CreateCertificate", "Successfully created Certificate %q", crt.Name)
+		return nil
+	}
+	if err != nil {
+		return err
 	}
 
+	if !metav1.IsControlledBy(existingCrt, ing) {
+		c.Recorder.Eventf(ing, corev1.EventTypeWarning, "CertificateNotOwned", "Certificate %q for this Ingress already exists but is not owned by this Ingress, refusing to update it", crt.Name)
+		return nil
+	}
+
+	if !certificateNeedsUpdate(existingCrt.Spec, crt.Spec) {
+		return nil
+	}
+
+	updateCrt := existingCrt.DeepCopy()
+	mergeCertificateSpec(&updateCrt.Spec, crt.Spec)
+	_, err = c.CMClient.CertmanagerV1alpha1().Certificates(crt.Namespace).Update(updateCrt)
+	if err != nil {
+		return err
+	}
+	c.Recorder.Eventf(ing, corev1.EventTypeNormal, "UpdateCertificate", "Successfully updated Certificate %q", crt.Name)
 	return nil
 }
 
-func (c *Controller) buildCertificates(ing *extv1beta1.Ingress) ([]*v1alpha1.Certificate, error) {
+// certificateNeedsUpdate reports whether existing's spec needs to change to
+// match desired. DNSNames, SecretName, IssuerRef and ACME config are always
+// owned by ingress-shim and compared directly. The remaining fields are only
+// ever set by ingress-shim when the corresponding Ingress annotation is
+// present, so they are compared only when desired carries a non-zero value
+// for them; otherwise server-side defaulting (or a value set by some other
+// means) would never match desired's zero value and every resync would
+// produce a spurious Update.
+func certificateNeedsUpdate(existing, desired v1alpha1.CertificateSpec) bool {
+	if !reflect.DeepEqual(existing.DNSNames, desired.DNSNames) {
+		return true
+	}
+	if existing.SecretName != desired.SecretName {
+		return true
+	}
+	if !reflect.DeepEqual(existing.IssuerRef, desired.IssuerRef) {
+		return true
+	}
+	if !reflect.DeepEqual(existing.ACME, desired.ACME) {
+		return true
+	}
+	if desired.CommonName != "" && existing.CommonName != desired.CommonName {
+		return true
+	}
+	if len(desired.Organization) > 0 && !reflect.DeepEqual(existing.Organization, desired.Organization) {
+		return true
+	}
+	if desired.Duration != nil && (existing.Duration == nil || *existing.Duration != *desired.Duration) {
+		return true
+	}
+	if desired.KeySize != 0 && existing.KeySize != desired.KeySize {
+		return true
+	}
+	if desired.KeyAlgorithm != "" && existing.KeyAlgorithm != desired.KeyAlgorithm {
+		return true
+	}
+	if len(desired.IPAddresses) > 0 && !reflect.DeepEqual(existing.IPAddresses, desired.IPAddresses) {
+		return true
+	}
+	return false
+}
+
+// mergeCertificateSpec applies desired onto existing following the same
+// ownership rules as certificateNeedsUpdate: always-owned fields are
+// replaced outright, while annotation-driven fields are only overwritten
+// when desired actually carries a value for them.
+func mergeCertificateSpec(existing *v1alpha1.CertificateSpec, desired v1alpha1.CertificateSpec) {
+	existing.DNSNames = desired.DNSNames
+	existing.SecretName = desired.SecretName
+	existing.IssuerRef = desired.IssuerRef
+	existing.ACME = desired.ACME
+	if desired.CommonName != "" {
+		existing.CommonName = desired.CommonName
+	}
+	if len(desired.Organization) > 0 {
+		existing.Organization = desired.Organization
+	}
+	if desired.Duration != nil {
+		existing.Duration = desired.Duration
+	}
+	if desired.KeySize != 0 {
+		existing.KeySize = desired.KeySize
+	}
+	if desired.KeyAlgorithm != "" {
+		existing.KeyAlgorithm = desired.KeyAlgorithm
+	}
+	if len(desired.IPAddresses) > 0 {
+		existing.IPAddresses = desired.IPAddresses
+	}
+}
+
+// garbageCollectCertificates deletes any Certificate owned by ing that is
+// not present in desired, e.g. because its TLS entry was removed from the
+// Ingress or the Ingress no longer has cert-manager annotations at all (in
+// which case desired is nil).
+func (c *Controller) garbageCollectCertificates(ing ingressLike, desired []*v1alpha1.Certificate) error {
+	wanted := make(map[string]bool, len(desired))
+	for _, crt := range desired {
+		wanted[crt.Name] = true
+	}
+
+	existing, err := c.certificateLister.Certificates(ing.GetNamespace()).List(labels.Everything())
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, crt := range existing {
+		if !metav1.IsControlledBy(crt, ing) || wanted[crt.Name] {
+			continue
+		}
+		if err := c.CMClient.CertmanagerV1alpha1().Certificates(crt.Namespace).Delete(crt.Name, nil); err != nil && !apierrors.IsNotFound(err) {
+			errs = append(errs, err)
+			continue
+		}
+		c.Recorder.Eventf(ing, corev1.EventTypeNormal, "DeleteCertificate", "Successfully deleted unused Certificate %q", crt.Name)
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+func (c *Controller) buildCertificates(ing ingressLike) ([]*v1alpha1.Certificate, error) {
 	issuerName, issuerKind := c.issuerForIngress(ing)
-	issuer, err := c.getGenericIssuer(ing.Namespace, issuerName, issuerKind)
+	issuer, err := c.getGenericIssuer(ing.GetNamespace(), issuerName, issuerKind)
 	if err != nil {
 		return nil, err
 	}
 
 	var crts []*v1alpha1.Certificate
-	for i, tls := range ing.Spec.TLS {
-		// validate the ingress TLS block
+	for i, tls := range ing.TLSEntries() {
+		// validate the TLS entry
 		if len(tls.Hosts) == 0 {
-			return nil, fmt.Errorf("secret %q for ingress %q has no hosts specified", tls.SecretName, ing.Name)
+			if ing.GroupVersionKind().Kind != "Ingress" {
+				// unlike an Ingress, a Gateway listener can legitimately have
+				// a TLS certificateRef with no concrete Hostname and no
+				// HTTPRoute attached to it yet; failing the whole Gateway
+				// until a route shows up would also block every other,
+				// already-resolvable listener on it, so just skip this entry
+				// until its hosts are known.
+				glog.Infof("Skipping TLS entry %d (secret %q) for %s %q as it has no hosts resolved yet", i, tls.SecretName, ing.GroupVersionKind().Kind, ing.GetName())
+				continue
+			}
+			return nil, fmt.Errorf("secret %q for %s %q has no hosts specified", tls.SecretName, ing.GroupVersionKind().Kind, ing.GetName())
 		}
 		if tls.SecretName == "" {
-			return nil, fmt.Errorf("TLS entry %d for ingress %q must specify a secretName", i, ing.Name)
-		}
-
-		// check if a Certificate for this TLS entry already exists, and if it
-		// does then skip this entry
-		existingCrt, err := c.certificateLister.Certificates(ing.Namespace).Get(tls.SecretName)
-		if !apierrors.IsNotFound(err) && err != nil {
-			return nil, err
-		}
-		if existingCrt != nil {
-			glog.Infof("Certificate %q for ingress %q already exists, not re-creating", tls.SecretName, ing.Name)
-			continue
+			return nil, fmt.Errorf("TLS entry %d for %s %q must specify a secretName", i, ing.GroupVersionKind().Kind, ing.GetName())
 		}
 
 		crt := &v1alpha1.Certificate{
 			ObjectMeta: metav1.ObjectMeta{
 				Name:            tls.SecretName,
-				Namespace:       ing.Namespace,
-				OwnerReferences: []metav1.OwnerReference{*metav1.NewControllerRef(ing, ingressGVK)},
+				Namespace:       ing.GetNamespace(),
+				OwnerReferences: []metav1.OwnerReference{*metav1.NewControllerRef(ing, ing.GroupVersionKind())},
 			},
 			Spec: v1alpha1.CertificateSpec{
 				DNSNames:   tls.Hosts,
@@ -111,44 +305,222 @@ func (c *Controller) buildCertificates(ing *extv1beta1.Ingress) ([]*v1alpha1.Cer
 	return crts, nil
 }
 
-func (c *Controller) setIssuerSpecificConfig(crt *v1alpha1.Certificate, issuer v1alpha1.GenericIssuer, ing *extv1beta1.Ingress, tls extv1beta1.IngressTLS) error {
-	ingAnnotations := ing.Annotations
+func (c *Controller) setIssuerSpecificConfig(crt *v1alpha1.Certificate, issuer v1alpha1.GenericIssuer, ing ingressLike, tls ingressLikeTLS) error {
+	ingAnnotations := ing.GetAnnotations()
 	if ingAnnotations == nil {
 		ingAnnotations = map[string]string{}
 	}
-	// for ACME issuers
-	if issuer.GetSpec().ACME != nil {
-		challengeType, ok := ingAnnotations[acmeIssuerChallengeTypeAnnotation]
-		if !ok {
-			challengeType = c.options.DefaultACMEIssuerChallengeType
-		}
-		domainCfg := v1alpha1.ACMECertificateDomainConfig{
-			Domains: tls.Hosts,
-		}
-		switch challengeType {
-		case "http01":
-			domainCfg.HTTP01 = &v1alpha1.ACMECertificateHTTP01Config{Ingress: ing.Name}
-		case "dns01":
-			dnsProvider, ok := ingAnnotations[acmeIssuerDNS01ProviderNameAnnotation]
-			if !ok {
-				dnsProvider = c.options.DefaultACMEIssuerDNS01ProviderName
-			}
-			if dnsProvider == "" {
-				return fmt.Errorf("no acme issuer dns01 challenge provider specified")
+
+	spec := issuer.GetSpec()
+	switch {
+	case spec.ACME != nil:
+		domainCfgs, err := c.acmeDomainConfigsForIngress(ing, ingAnnotations, tls.Hosts)
+		if err != nil {
+			return err
+		}
+		crt.Spec.ACME = &v1alpha1.ACMECertificateConfig{Config: domainCfgs}
+	case spec.CA != nil, spec.Vault != nil, spec.SelfSigned != nil:
+		// these issuer kinds don't have any ACME-specific config to set on
+		// the Certificate; the common CertificateSpec fields populated
+		// below (CommonName, Organization, Duration, ...) are all that's
+		// needed to drive them from Ingress annotations.
+	default:
+		c.Recorder.Eventf(ing, corev1.EventTypeWarning, "UnsupportedIssuer", "Issuer %q has a type that ingress-shim does not support", crt.Spec.IssuerRef.Name)
+		return fmt.Errorf("issuer %q has a type that ingress-shim does not support", crt.Spec.IssuerRef.Name)
+	}
+
+	return applyCommonAnnotations(crt, ingAnnotations)
+}
+
+// applyCommonAnnotations copies the Ingress annotations that apply to every
+// issuer kind (CommonName, Organization, Duration, KeySize, KeyAlgorithm,
+// IPAddresses, ...) onto the generated Certificate's spec.
+//
+// renew-before, key-encoding, usages, uri-sans and revision-history-limit are
+// deliberately not supported here: this tree vendors the early
+// ACMECertificateConfig-era v1alpha1 API, whose CertificateSpec predates
+// those fields (RenewBefore, KeyEncoding, Usages, URISANs and
+// RevisionHistoryLimit were all added in later cert-manager releases), so
+// there is nothing on CertificateSpec for the corresponding annotations to
+// set.
+func applyCommonAnnotations(crt *v1alpha1.Certificate, ingAnnotations map[string]string) error {
+	if commonName, ok := ingAnnotations[commonNameAnnotation]; ok {
+		crt.Spec.CommonName = commonName
+	}
+	if organization, ok := ingAnnotations[organizationAnnotation]; ok {
+		crt.Spec.Organization = strings.Split(organization, ",")
+	}
+	if durationStr, ok := ingAnnotations[durationAnnotation]; ok {
+		duration, err := time.ParseDuration(durationStr)
+		if err != nil {
+			return fmt.Errorf("failed to parse %q annotation: %v", durationAnnotation, err)
+		}
+		crt.Spec.Duration = &metav1.Duration{Duration: duration}
+	}
+	if keySizeStr, ok := ingAnnotations[keySizeAnnotation]; ok {
+		keySize, err := strconv.Atoi(keySizeStr)
+		if err != nil {
+			return fmt.Errorf("failed to parse %q annotation: %v", keySizeAnnotation, err)
+		}
+		crt.Spec.KeySize = keySize
+	}
+	if keyAlgorithm, ok := ingAnnotations[keyAlgorithmAnnotation]; ok {
+		crt.Spec.KeyAlgorithm = v1alpha1.KeyAlgorithm(keyAlgorithm)
+	}
+	if ipSANs, ok := ingAnnotations[ipSANsAnnotation]; ok {
+		crt.Spec.IPAddresses = strings.Split(ipSANs, ",")
+	}
+	return nil
+}
+
+// acmeDomainConfigsForIngress builds the set of ACMECertificateDomainConfig
+// entries for the given TLS hosts. If the acmeIssuerChallengeSolversAnnotation
+// annotation is present, hosts are partitioned across the rules it defines so
+// that e.g. wildcard hosts can use dns01 while other hosts on the same
+// Ingress use http01. Otherwise, a single challenge type (as configured via
+// acmeIssuerChallengeTypeAnnotation) is used for all of the given hosts.
+func (c *Controller) acmeDomainConfigsForIngress(ing ingressLike, ingAnnotations map[string]string, hosts []string) ([]v1alpha1.ACMECertificateDomainConfig, error) {
+	rulesJSON, ok := ingAnnotations[acmeIssuerChallengeSolversAnnotation]
+	if !ok {
+		domainCfg, err := c.acmeDomainConfigForChallengeType(ing, ingAnnotations, hosts, ingAnnotations[acmeIssuerChallengeTypeAnnotation])
+		if err != nil {
+			return nil, err
+		}
+		return []v1alpha1.ACMECertificateDomainConfig{domainCfg}, nil
+	}
+
+	var rules []solverHostRule
+	if err := json.Unmarshal([]byte(rulesJSON), &rules); err != nil {
+		return nil, fmt.Errorf("failed to parse %q annotation: %v", acmeIssuerChallengeSolversAnnotation, err)
+	}
+
+	partitions, err := partitionHostsBySolverRules(rules, hosts)
+	if err != nil {
+		return nil, fmt.Errorf("%s %q: %v", ing.GroupVersionKind().Kind, ing.GetName(), err)
+	}
+
+	var domainCfgs []v1alpha1.ACMECertificateDomainConfig
+	for i, rule := range rules {
+		ruleHosts := partitions[i]
+		if len(ruleHosts) == 0 {
+			continue
+		}
+
+		var challengeType string
+		switch {
+		case rule.DNS01 != "":
+			challengeType = "dns01"
+		case rule.HTTP01:
+			challengeType = "http01"
+		default:
+			return nil, fmt.Errorf("solver rule %d in the %q annotation specifies neither http01 nor dns01", i, acmeIssuerChallengeSolversAnnotation)
+		}
+		annotations := ingAnnotations
+		if rule.DNS01 != "" {
+			annotations = map[string]string{acmeIssuerDNS01ProviderNameAnnotation: rule.DNS01}
+		}
+		domainCfg, err := c.acmeDomainConfigForChallengeType(ing, annotations, ruleHosts, challengeType)
+		if err != nil {
+			return nil, fmt.Errorf("solver rule %d: %v", i, err)
+		}
+		domainCfgs = append(domainCfgs, domainCfg)
+	}
+
+	return domainCfgs, nil
+}
+
+// partitionHostsBySolverRules validates that every host in hosts is covered
+// by exactly one rule in rules, and returns, keyed by rule index, the subset
+// of hosts that rule covers. It has no *Controller dependency so the
+// overlap/unmatched validation can be unit tested on its own.
+func partitionHostsBySolverRules(rules []solverHostRule, hosts []string) (map[int][]string, error) {
+	wanted := map[string]bool{}
+	for _, h := range hosts {
+		wanted[h] = true
+	}
+
+	// index which rules claim each of the wanted hosts before building any
+	// partition, so that a host matched by more than one rule is rejected
+	// rather than silently resolved by whichever rule saw it first.
+	matchedBy := map[string][]int{}
+	for i, rule := range rules {
+		for _, h := range rule.Hosts {
+			if !wanted[h] {
+				continue
 			}
-			domainCfg.DNS01 = &v1alpha1.ACMECertificateDNS01Config{Provider: dnsProvider}
+			matchedBy[h] = append(matchedBy[h], i)
+		}
+	}
+
+	var overlaps []string
+	var unmatched []string
+	for _, h := range hosts {
+		switch len(matchedBy[h]) {
+		case 0:
+			unmatched = append(unmatched, h)
+		case 1:
 		default:
-			return fmt.Errorf("invalid acme issuer challenge type specified %q", challengeType)
+			overlaps = append(overlaps, fmt.Sprintf("%q (rules %v)", h, matchedBy[h]))
 		}
-		crt.Spec.ACME = &v1alpha1.ACMECertificateConfig{Config: []v1alpha1.ACMECertificateDomainConfig{domainCfg}}
 	}
-	return nil
+	if len(overlaps) > 0 {
+		return nil, fmt.Errorf("hosts are matched by more than one rule in the %q annotation: %s", acmeIssuerChallengeSolversAnnotation, strings.Join(overlaps, ", "))
+	}
+	if len(unmatched) > 0 {
+		return nil, fmt.Errorf("hosts %v are not covered by any rule in the %q annotation", unmatched, acmeIssuerChallengeSolversAnnotation)
+	}
+
+	partitions := make(map[int][]string, len(rules))
+	for i, rule := range rules {
+		for _, h := range rule.Hosts {
+			if wanted[h] {
+				partitions[i] = append(partitions[i], h)
+			}
+		}
+	}
+	return partitions, nil
+}
+
+// acmeDomainConfigForChallengeType builds a single ACMECertificateDomainConfig
+// covering hosts, using challengeType (falling back to the controller's
+// configured default if empty).
+func (c *Controller) acmeDomainConfigForChallengeType(ing ingressLike, ingAnnotations map[string]string, hosts []string, challengeType string) (v1alpha1.ACMECertificateDomainConfig, error) {
+	if challengeType == "" {
+		challengeType = c.options.DefaultACMEIssuerChallengeType
+	}
+	domainCfg := v1alpha1.ACMECertificateDomainConfig{
+		Domains: hosts,
+	}
+	switch challengeType {
+	case "http01":
+		// the http01 solver edits an Ingress resource in place to route the
+		// ACME HTTP-01 challenge traffic, so it only makes sense for sources
+		// that are themselves an Ingress.
+		if ing.GroupVersionKind().Kind != "Ingress" {
+			return v1alpha1.ACMECertificateDomainConfig{}, fmt.Errorf("http01 challenges require an Ingress resource to attach the solver to; %s %q must use dns01 instead", ing.GroupVersionKind().Kind, ing.GetName())
+		}
+		domainCfg.HTTP01 = &v1alpha1.ACMECertificateHTTP01Config{Ingress: ing.GetName()}
+	case "dns01":
+		dnsProvider, ok := ingAnnotations[acmeIssuerDNS01ProviderNameAnnotation]
+		if !ok {
+			dnsProvider = c.options.DefaultACMEIssuerDNS01ProviderName
+		}
+		if dnsProvider == "" {
+			return v1alpha1.ACMECertificateDomainConfig{}, fmt.Errorf("no acme issuer dns01 challenge provider specified")
+		}
+		domainCfg.DNS01 = &v1alpha1.ACMECertificateDNS01Config{Provider: dnsProvider}
+	default:
+		return v1alpha1.ACMECertificateDomainConfig{}, fmt.Errorf("invalid acme issuer challenge type specified %q", challengeType)
+	}
+	return domainCfg, nil
 }
 
 // shouldSync returns true if this ingress should have a Certificate resource
-// created for it
-func shouldSync(ing *extv1beta1.Ingress) bool {
-	annotations := ing.Annotations
+// created for it. Callers must check ingressClassMatches separately: unlike
+// a missing annotation, a non-matching class must not trigger garbage
+// collection (see Sync).
+func (c *Controller) shouldSync(ing ingressLike) bool {
+	annotations := ing.GetAnnotations()
 	if annotations == nil {
 		annotations = map[string]string{}
 	}
@@ -172,13 +544,27 @@ func shouldSync(ing *extv1beta1.Ingress) bool {
 	return false
 }
 
+// ingressClassMatches returns true if the Ingress's kubernetes.io/ingress.class
+// annotation matches the controller's configured --ingress-class option. An
+// empty --ingress-class option means "any", so every Ingress matches.
+func (c *Controller) ingressClassMatches(ing ingressLike) bool {
+	if c.options.IngressClass == "" {
+		return true
+	}
+	annotations := ing.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	return annotations[ingressClassAnnotation] == c.options.IngressClass
+}
+
 // issuerForIngress will determine the issuer that should be specified on a
 // Certificate created for the given Ingress resource. If one is not set, the
 // default issuer given to the controller will be used.
-func (c *Controller) issuerForIngress(ing *extv1beta1.Ingress) (name string, kind string) {
+func (c *Controller) issuerForIngress(ing ingressLike) (name string, kind string) {
 	name = c.options.DefaultIssuerName
 	kind = c.options.DefaultIssuerKind
-	annotations := ing.Annotations
+	annotations := ing.GetAnnotations()
 	if annotations == nil {
 		annotations = map[string]string{}
 	}
@@ -0,0 +1,211 @@
+package controller
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jetstack/cert-manager/pkg/apis/certmanager/v1alpha1"
+)
+
+func TestPartitionHostsBySolverRules(t *testing.T) {
+	tests := map[string]struct {
+		rules   []solverHostRule
+		hosts   []string
+		want    map[int][]string
+		wantErr bool
+	}{
+		"single rule covers every host": {
+			rules: []solverHostRule{
+				{Hosts: []string{"example.com", "www.example.com"}, HTTP01: true},
+			},
+			hosts: []string{"example.com", "www.example.com"},
+			want:  map[int][]string{0: {"example.com", "www.example.com"}},
+		},
+		"disjoint rules partition hosts": {
+			rules: []solverHostRule{
+				{Hosts: []string{"*.example.com"}, DNS01: "route53"},
+				{Hosts: []string{"example.com"}, HTTP01: true},
+			},
+			hosts: []string{"*.example.com", "example.com"},
+			want: map[int][]string{
+				0: {"*.example.com"},
+				1: {"example.com"},
+			},
+		},
+		"host matched by more than one rule is rejected": {
+			rules: []solverHostRule{
+				{Hosts: []string{"example.com"}, HTTP01: true},
+				{Hosts: []string{"example.com"}, DNS01: "route53"},
+			},
+			hosts:   []string{"example.com"},
+			wantErr: true,
+		},
+		"host matched by no rule is rejected": {
+			rules: []solverHostRule{
+				{Hosts: []string{"example.com"}, HTTP01: true},
+			},
+			hosts:   []string{"example.com", "other.example.com"},
+			wantErr: true,
+		},
+		"a rule's hosts outside the wanted set are ignored": {
+			rules: []solverHostRule{
+				{Hosts: []string{"example.com", "unrelated.example.com"}, HTTP01: true},
+			},
+			hosts: []string{"example.com"},
+			want:  map[int][]string{0: {"example.com"}},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := partitionHostsBySolverRules(test.rules, test.hosts)
+			if test.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got none (result: %#v)", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %#v, want %#v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestApplyCommonAnnotations(t *testing.T) {
+	tests := map[string]struct {
+		annotations map[string]string
+		wantErr     bool
+		check       func(t *testing.T, crt *v1alpha1.Certificate)
+	}{
+		"no annotations leaves the spec untouched": {
+			annotations: map[string]string{},
+			check: func(t *testing.T, crt *v1alpha1.Certificate) {
+				if crt.Spec.CommonName != "" || crt.Spec.Organization != nil || crt.Spec.Duration != nil || crt.Spec.KeySize != 0 || crt.Spec.KeyAlgorithm != "" {
+					t.Errorf("expected an untouched spec, got %#v", crt.Spec)
+				}
+			},
+		},
+		"common-name annotation sets CommonName": {
+			annotations: map[string]string{commonNameAnnotation: "example.com"},
+			check: func(t *testing.T, crt *v1alpha1.Certificate) {
+				if crt.Spec.CommonName != "example.com" {
+					t.Errorf("got CommonName %q, want %q", crt.Spec.CommonName, "example.com")
+				}
+			},
+		},
+		"organization annotation is split on commas": {
+			annotations: map[string]string{organizationAnnotation: "Acme Co,Acme Widgets"},
+			check: func(t *testing.T, crt *v1alpha1.Certificate) {
+				want := []string{"Acme Co", "Acme Widgets"}
+				if !reflect.DeepEqual(crt.Spec.Organization, want) {
+					t.Errorf("got Organization %v, want %v", crt.Spec.Organization, want)
+				}
+			},
+		},
+		"key-size annotation sets KeySize": {
+			annotations: map[string]string{keySizeAnnotation: "4096"},
+			check: func(t *testing.T, crt *v1alpha1.Certificate) {
+				if crt.Spec.KeySize != 4096 {
+					t.Errorf("got KeySize %d, want 4096", crt.Spec.KeySize)
+				}
+			},
+		},
+		"key-size annotation with an invalid value is rejected": {
+			annotations: map[string]string{keySizeAnnotation: "not-a-number"},
+			wantErr:     true,
+		},
+		"duration annotation with an invalid value is rejected": {
+			annotations: map[string]string{durationAnnotation: "not-a-duration"},
+			wantErr:     true,
+		},
+		"ip-sans annotation is split on commas into IPAddresses": {
+			annotations: map[string]string{ipSANsAnnotation: "10.0.0.1,10.0.0.2"},
+			check: func(t *testing.T, crt *v1alpha1.Certificate) {
+				want := []string{"10.0.0.1", "10.0.0.2"}
+				if !reflect.DeepEqual(crt.Spec.IPAddresses, want) {
+					t.Errorf("got IPAddresses %v, want %v", crt.Spec.IPAddresses, want)
+				}
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			crt := &v1alpha1.Certificate{}
+			err := applyCommonAnnotations(crt, test.annotations)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("applyCommonAnnotations() error = %v, wantErr %v", err, test.wantErr)
+			}
+			if err == nil && test.check != nil {
+				test.check(t, crt)
+			}
+		})
+	}
+}
+
+func TestCertificateNeedsUpdate(t *testing.T) {
+	tests := map[string]struct {
+		existing v1alpha1.CertificateSpec
+		desired  v1alpha1.CertificateSpec
+		want     bool
+	}{
+		"identical specs need no update": {
+			existing: v1alpha1.CertificateSpec{DNSNames: []string{"example.com"}, SecretName: "example-tls"},
+			desired:  v1alpha1.CertificateSpec{DNSNames: []string{"example.com"}, SecretName: "example-tls"},
+			want:     false,
+		},
+		"changed DNSNames needs an update": {
+			existing: v1alpha1.CertificateSpec{DNSNames: []string{"example.com"}},
+			desired:  v1alpha1.CertificateSpec{DNSNames: []string{"example.com", "www.example.com"}},
+			want:     true,
+		},
+		"desired leaving an annotation-driven field unset does not trigger an update": {
+			// the existing spec has a CommonName set (e.g. via a now-removed
+			// annotation, or server-side defaulting); desired carries no
+			// opinion on CommonName, so it must not be clobbered or flagged.
+			existing: v1alpha1.CertificateSpec{CommonName: "example.com"},
+			desired:  v1alpha1.CertificateSpec{},
+			want:     false,
+		},
+		"desired setting an annotation-driven field needs an update": {
+			existing: v1alpha1.CertificateSpec{CommonName: "example.com"},
+			desired:  v1alpha1.CertificateSpec{CommonName: "other.example.com"},
+			want:     true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := certificateNeedsUpdate(test.existing, test.desired); got != test.want {
+				t.Errorf("certificateNeedsUpdate() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestMergeCertificateSpec(t *testing.T) {
+	existing := v1alpha1.CertificateSpec{
+		DNSNames:   []string{"example.com"},
+		SecretName: "example-tls",
+		CommonName: "example.com",
+	}
+	desired := v1alpha1.CertificateSpec{
+		DNSNames:   []string{"example.com", "www.example.com"},
+		SecretName: "example-tls",
+	}
+
+	mergeCertificateSpec(&existing, desired)
+
+	if !reflect.DeepEqual(existing.DNSNames, desired.DNSNames) {
+		t.Errorf("got DNSNames %v, want %v", existing.DNSNames, desired.DNSNames)
+	}
+	// desired carries no CommonName, so the field ingress-shim doesn't own
+	// here must survive the merge rather than being zeroed out.
+	if existing.CommonName != "example.com" {
+		t.Errorf("got CommonName %q, want it preserved as %q", existing.CommonName, "example.com")
+	}
+}